@@ -7,170 +7,226 @@ remain bounded in absolute value. The sequence "Zn" is defined by:
 Z(0) = 0
 Z(n+1) = Z(n)^2 + c
 
-The task of rendering the image is split into regions of the image(WorkRange)
-and each task is given to a new goroutine(calculatorWorker) to calculate. When a
-calculatorWorker has finished calculating a region a new regoin gets assigned to it.
+A render is described by a Viewport (see viewport.go), which drives the
+worker/channel pipeline in scheduler.go: the image is split into regions
+(workRange) that a pool of calculatorWorker goroutines render via a
+Mariani-Silver subdivision scheduler, recursively splitting regions whose
+border isn't uniform until they're small enough to compute pixel-by-pixel.
 */
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"image"
-	"image/color"
 	"image/png"
-	"math"
 	"os"
-	"sync"
+	"sync/atomic"
 )
 
 // Struct for work item
 type workRange struct {
 	minX, maxX, minY, maxY int
+	samples                int // supersampling factor: samples*samples points per pixel
 }
 
-type pixel struct {
-	x, y int
-	col  color.RGBA
+// renderParams carries the per-render values a Viewport resolves its
+// CenterRe/CenterIm/Zoom/MaxIter into: the plane bounds and pixel grid that
+// workCreator and calculatorWorker actually operate on.
+type renderParams struct {
+	reStart, reEnd, imStart, imEnd float64
+	imgWidth, imgHeight            int
+	maxIter                        int
 }
 
-// Image and concurrency parameters
+// Concurrency and debug parameters shared by every render.
 var (
-	MAX_ITER  int = 255
 	IMG_SCALE int = 10
 
-	RE_START float64 = -2.
-	RE_END   float64 = 1.
-	IM_START float64 = -1.
-	IM_END   float64 = 1.
-	bound    float64 = 2
+	numThreads int = 12
 
-	width       int = 600 * IMG_SCALE
-	height      int = 400 * IMG_SCALE
-	numOfPixels     = width * height
+	// SAMPLES is the supersampling factor: each pixel is evaluated on a
+	// SAMPLES x SAMPLES sub-pixel grid and the results averaged in linear
+	// space for anti-aliasing. 1 disables supersampling.
+	SAMPLES int = 1
 
-	numWorkTasks int = 64
-	numThreads   int = 12
-
-	printCalculatingProgress bool = true
-	printDrawingProgress     bool = true
-	printWorkItems           bool = false
+	printDrawingProgress bool = true
 )
 
-func mandelbrotCalc(c complex64) int {
-	z := complex64(0)
-	n := 0
-	for math.Abs(float64(real(z))) <= bound && n < MAX_ITER {
-		z = z*z + c
-		n += 1
-	}
-	return n
+// groupSize is the number of pixels calculated together by a KernelFunc.
+// Keeping the lanes in fixed-size arrays lets the escape-time loop stay
+// branch-free (aside from the alive bitmask) until every lane has either
+// escaped or hit MAX_ITER, which is friendlier to the compiler's
+// auto-vectorizer than computing one complex number at a time.
+const groupSize = 8
+
+// escapeResult is a single lane's outcome from a KernelFunc: the iteration
+// it escaped at (or maxIter if it never did) and |z|^2 at that point, which
+// is all a Palette needs to compute a smooth iteration count.
+type escapeResult struct {
+	iter int
+	abs2 float64
 }
-func calculatorWorker(work workRange, pixelBuffer chan pixel, freeThreads chan bool) {
-	for x := work.minX; x < work.maxX; x++ {
-		for y := work.minY; y < work.maxY; y++ {
-			complNum := complex(RE_START+float64(x)/float64(width)*(RE_END-RE_START),
-				IM_START+float64(y)/float64(height)*(IM_END-IM_START))
-			val := mandelbrotCalc(complex64(complNum))
-			val = 255 - int(val*255/MAX_ITER)
-			col := color.RGBA{uint8(val), uint8(val), uint8(val), 254}
-			pixelBuffer <- pixel{x, y, col}
-		}
+
+// KernelFunc computes the escape-time outcome for groupSize points of the
+// complex plane at once. pixel_Group_r/pixel_Group_i hold the real and
+// imaginary parts of c for each lane.
+type KernelFunc func(pixel_Group_r, pixel_Group_i [groupSize]float64, maxIter int) [groupSize]escapeResult
+
+// inMainCardioidOrBulb reports whether c = x+iy lies in the main cardioid or
+// the period-2 bulb, the two largest regions of the set. Points inside them
+// never escape, so callers can skip iterating them entirely.
+func inMainCardioidOrBulb(x, y float64) bool {
+	q := (x-0.25)*(x-0.25) + y*y
+	if q*(q+(x-0.25)) < 0.25*y*y {
+		return true
 	}
-	freeThreads <- true
+	if (x+1)*(x+1)+y*y < 1.0/16.0 {
+		return true
+	}
+	return false
 }
-func drawingWorker(pixelBuffer chan pixel, image *image.RGBA, pixelCount *int, wg *sync.WaitGroup) {
-	for p := range pixelBuffer {
-		image.Set(p.x, p.y, p.col)
-		*pixelCount++
-		if printDrawingProgress && *pixelCount%(numOfPixels/10) == 0 {
-			fmt.Print("█")
-		}
-		if *pixelCount == numOfPixels {
-			break
+
+// mandelbrotCalc is the default KernelFunc. It culls points inside the main
+// cardioid/period-2 bulb up front, then iterates the remaining lanes with
+// the standard real-valued escape-time recurrence
+// (Zr, Zi) = (Zr^2 - Zi^2 + Cr, 2*Zr*Zi + Ci), bailing a lane out once
+// Zr^2+Zi^2 > 4.
+func mandelbrotCalc(pixel_Group_r, pixel_Group_i [groupSize]float64, maxIter int) [groupSize]escapeResult {
+	var zr, zi [groupSize]float64
+	var result [groupSize]escapeResult
+	var alive uint8 = 1<<groupSize - 1
+
+	for lane := 0; lane < groupSize; lane++ {
+		if inMainCardioidOrBulb(pixel_Group_r[lane], pixel_Group_i[lane]) {
+			result[lane].iter = maxIter
+			alive &^= 1 << lane
 		}
 	}
-	fmt.Println()
-	wg.Done()
-}
 
-func workCreator(workBuffer chan workRange) {
-	r := int(math.Sqrt(float64(numWorkTasks)))
-	work_width := width / r
-	work_height := height / r
-
-	for i := 0; i < r; i++ {
-		for j := 0; j < r; j++ {
-			w := workRange{i * work_width, (i + 1) * work_width,
-				j * work_height, (j + 1) * work_height}
-			if printWorkItems {
-				fmt.Println(w)
+	for n := 0; n < maxIter && alive != 0; n++ {
+		for lane := 0; lane < groupSize; lane++ {
+			mask := uint8(1) << lane
+			if alive&mask == 0 {
+				continue
+			}
+			tr := zr[lane] * zr[lane]
+			ti := zi[lane] * zi[lane]
+			if tr+ti > 4 {
+				result[lane] = escapeResult{iter: n, abs2: tr + ti}
+				alive &^= mask
+				continue
 			}
-			workBuffer <- w
+			zi[lane] = 2*zr[lane]*zi[lane] + pixel_Group_i[lane]
+			zr[lane] = tr - ti + pixel_Group_r[lane]
 		}
 	}
-
+	for lane := 0; lane < groupSize; lane++ {
+		if alive&(1<<lane) != 0 {
+			result[lane].iter = maxIter
+		}
+	}
+	return result
 }
 
-func calculatorWorkerStarter(workBuffer chan workRange, pixelBuffer chan pixel, freeThreads chan bool) {
-	for t := 0; t < numThreads; t++ {
-		freeThreads <- true
-	}
-	workCounter := numWorkTasks
-	for w := range workBuffer { // For every work task(workRange) start a goroutine
-		<-freeThreads // continue execution when theres a free thread
-		go calculatorWorker(w, pixelBuffer, freeThreads)
-		workCounter--
-		if workCounter == 0 {
-			return
+// evaluatedPoints counts every point actually run through a KernelFunc.
+// It exists to measure how many escape-time evaluations a scheduler saves
+// by culling interior/exterior regions instead of computing every pixel;
+// see BenchmarkUniformTiler/BenchmarkMarianiSilver in scheduler_test.go.
+var evaluatedPoints int64
+
+// evalPoints runs an arbitrary number of points through kernel, internally
+// batching them into groupSize-wide lane groups (the last group is padded by
+// repeating its final point, whose extra results are simply discarded).
+func evalPoints(cr, ci []float64, kernel KernelFunc, maxIter int) []escapeResult {
+	atomic.AddInt64(&evaluatedPoints, int64(len(cr)))
+	results := make([]escapeResult, len(cr))
+	for i := 0; i < len(cr); i += groupSize {
+		var gr, gi [groupSize]float64
+		lanes := groupSize
+		if i+lanes > len(cr) {
+			lanes = len(cr) - i
 		}
-		if printCalculatingProgress && workCounter%(workCounter/10+1) == 0 {
-			fmt.Print("█")
+		copy(gr[:lanes], cr[i:i+lanes])
+		copy(gi[:lanes], ci[i:i+lanes])
+		for lane := lanes; lane < groupSize; lane++ {
+			gr[lane] = gr[lanes-1]
+			gi[lane] = gi[lanes-1]
 		}
+		group := kernel(gr, gi, maxIter)
+		copy(results[i:i+lanes], group[:lanes])
 	}
-	if printCalculatingProgress {
-		fmt.Println()
-	}
+	return results
 }
 
-func main() {
-	// Image initialization
-	upLeft := image.Point{0, 0}
-	lowRight := image.Point{width, height}
-	image := image.NewRGBA(image.Rectangle{upLeft, lowRight})
+// interactiveFrontend is nil unless this binary was built with -tags
+// pixelgl, in which case pixelgl.go's init registers the click-drag-to-pan,
+// scroll-to-zoom front-end here instead of rendering a single PNG.
+var interactiveFrontend func(Viewport)
+
+// runStitch handles `mandelbrot stitch -out tiles -dest out.png`, assembling
+// a TiledRenderer's output directory into a single PNG.
+func runStitch(args []string) {
+	fs := flag.NewFlagSet("stitch", flag.ExitOnError)
+	outDir := fs.String("out", "tiles", "directory of tiles written by -tiled")
+	dest := fs.String("dest", "mandelbrot_stitched.png", "path to write the assembled PNG to")
+	fs.Parse(args)
 
-	// How many pixels have been rendered
-	pixelCount := 0
+	if err := Stitch(*outDir, *dest); err != nil {
+		fmt.Println("err:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Image created:", *dest)
+}
 
-	// Initialize channels to pass work items, pixels to-be-rendered and threads who are free to start working
-	freeThreads := make(chan bool, numThreads)
-	pixelBuffer := make(chan pixel, numOfPixels)
-	workBuffer := make(chan workRange, numWorkTasks)
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "stitch" {
+		runStitch(os.Args[2:])
+		return
+	}
 
-	// Create work tasks to be distributed to threads to calculate
-	workCreator(workBuffer)
+	tiled := flag.Bool("tiled", false, "stream tiles to -out instead of rendering a single PNG")
+	resume := flag.Bool("resume", false, "with -tiled, skip tiles already recorded in -out's manifest")
+	outDir := flag.String("out", "tiles", "with -tiled, directory to write tile_{i}_{j}.png and manifest.json to")
+	tileSize := flag.Int("tilesize", 512, "with -tiled, pixel size of each square tile")
+	flag.Parse()
+
+	v := Viewport{
+		CenterRe: -0.5,
+		CenterIm: 0,
+		Zoom:     1,
+		Width:    600 * IMG_SCALE,
+		Height:   400 * IMG_SCALE,
+		MaxIter:  255,
+	}
 
-	// Give work tasks to workers
-	go calculatorWorkerStarter(workBuffer, pixelBuffer, freeThreads)
+	if interactiveFrontend != nil {
+		interactiveFrontend(v)
+		return
+	}
 
-	// Sync group is used to signal when the drawing thread has finished rendering the image
-	var wg sync.WaitGroup
-	wg.Add(1)
-	// Start drawing thread
-	go drawingWorker(pixelBuffer, image, &pixelCount, &wg)
+	if *tiled {
+		tr := TiledRenderer{Viewport: v, TileSize: *tileSize, OutDir: *outDir, Resume: *resume}
+		if err := tr.Render(context.Background()); err != nil {
+			fmt.Println("err:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Tiles written to", *outDir)
+		return
+	}
 
-	// Wait for drawing thread to render image
-	wg.Wait()
-	fmt.Println("Pixels rendered:", pixelCount)
+	img := v.Render(context.Background())
+	fmt.Println("Pixels rendered:", v.Width*v.Height)
 
 	// Create file
-	imageName := fmt.Sprintf("mandelbrot_%d_%d_%d.png", width, height, MAX_ITER)
+	imageName := fmt.Sprintf("mandelbrot_%d_%d_%d.png", v.Width, v.Height, v.MaxIter)
 	f, err := os.Create(imageName)
 	if err != nil {
 		// Print error if creating file failed
 		fmt.Println("err:", err)
 	} else {
 		// Encode file as PNG
-		png.Encode(f, image)
+		png.Encode(f, img)
 		fmt.Println("Image created:", imageName)
 	}
 }
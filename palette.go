@@ -0,0 +1,139 @@
+package main
+
+import (
+	"image/color"
+	"math"
+)
+
+// activePalette selects the colorer used by calculatorWorker.
+var activePalette Palette = BernsteinPalette{}
+
+// Palette maps a smooth (continuous) escape-time value to a color. mu is
+// produced by smoothIter and ranges from 0 up to maxIter; mu >= maxIter
+// means the point never escaped (i.e. it is considered part of the set).
+type Palette interface {
+	Color(mu float64, maxIter int) color.RGBA
+}
+
+// smoothIter turns a raw escapeResult into a continuous iteration count,
+// removing the banding a plain integer iteration count produces:
+//
+//	mu = n + 1 - log(log(|z|)) / log(2)
+//
+// Points that never escaped are returned as maxIter unchanged.
+func smoothIter(r escapeResult, maxIter int) float64 {
+	if r.iter >= maxIter {
+		return float64(maxIter)
+	}
+	return float64(r.iter) + 1 - math.Log(0.5*math.Log(r.abs2))/math.Log(2)
+}
+
+func clampByte(v float64) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+// GrayscalePalette reproduces the original "darker = closer to the set"
+// mapping, but driven by the smooth iteration count instead of the raw one.
+type GrayscalePalette struct{}
+
+func (GrayscalePalette) Color(mu float64, maxIter int) color.RGBA {
+	val := 255 - clampByte(mu*255/float64(maxIter))
+	return color.RGBA{val, val, val, 254}
+}
+
+// BernsteinPalette colors escaped points with the classic Bernstein
+// polynomial gradient (black -> blue -> white -> orange -> black), with t
+// the fraction of maxIter reached before escaping.
+type BernsteinPalette struct{}
+
+func (BernsteinPalette) Color(mu float64, maxIter int) color.RGBA {
+	if mu >= float64(maxIter) {
+		return color.RGBA{0, 0, 0, 254}
+	}
+	t := mu / float64(maxIter)
+	r := 9 * (1 - t) * t * t * t * 255
+	g := 15 * (1 - t) * (1 - t) * t * t * 255
+	b := 8.5 * (1 - t) * (1 - t) * (1 - t) * t * 255
+	return color.RGBA{clampByte(r), clampByte(g), clampByte(b), 254}
+}
+
+// HSVCyclePalette cycles escaped points through the hue wheel every Period
+// iterations, producing the banded rainbow look common in Mandelbrot
+// renderers. Points inside the set are rendered black.
+type HSVCyclePalette struct {
+	Period float64
+}
+
+func (p HSVCyclePalette) Color(mu float64, maxIter int) color.RGBA {
+	if mu >= float64(maxIter) {
+		return color.RGBA{0, 0, 0, 254}
+	}
+	period := p.Period
+	if period <= 0 {
+		period = float64(maxIter)
+	}
+	hue := math.Mod(mu, period) / period * 360
+	r, g, b := hsvToRGB(hue, 1, 1)
+	return color.RGBA{r, g, b, 254}
+}
+
+// hsvToRGB converts HSV (h in [0,360), s and v in [0,1]) to 8-bit RGB.
+func hsvToRGB(h, s, v float64) (uint8, uint8, uint8) {
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return clampByte((r + m) * 255), clampByte((g + m) * 255), clampByte((b + m) * 255)
+}
+
+// srgbToLinear and linearToSRGB let averageColor blend supersampled colors
+// in linear light instead of gamma space, which avoids the dark-fringing
+// that averaging raw sRGB bytes would otherwise introduce.
+func srgbToLinear(c uint8) float64 {
+	return math.Pow(float64(c)/255, 2.2)
+}
+
+func linearToSRGB(c float64) uint8 {
+	return clampByte(math.Pow(c, 1/2.2) * 255)
+}
+
+// averageColor colors each escapeResult with palette and averages the
+// results in linear space, implementing the supersampled anti-aliasing
+// pass described for calculatorWorker.
+func averageColor(results []escapeResult, palette Palette, maxIter int) color.RGBA {
+	var rSum, gSum, bSum float64
+	for _, r := range results {
+		col := palette.Color(smoothIter(r, maxIter), maxIter)
+		rSum += srgbToLinear(col.R)
+		gSum += srgbToLinear(col.G)
+		bSum += srgbToLinear(col.B)
+	}
+	n := float64(len(results))
+	return color.RGBA{
+		linearToSRGB(rSum / n),
+		linearToSRGB(gSum / n),
+		linearToSRGB(bSum / n),
+		254,
+	}
+}
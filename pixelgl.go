@@ -0,0 +1,96 @@
+//go:build pixelgl
+
+// This file adds an optional interactive front-end on top of Viewport using
+// github.com/faiface/pixel, as in the Difini/PixelGL reference. It is gated
+// behind the "pixelgl" build tag because the dependency pulls in CGO/OpenGL
+// bindings that aren't available in every build environment:
+//
+//	go get github.com/faiface/pixel/...
+//	go run -tags pixelgl .
+package main
+
+import (
+	"context"
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"golang.org/x/image/colornames"
+)
+
+func init() {
+	interactiveFrontend = runInteractive
+}
+
+// runInteractive opens a window showing v and lets the user click-drag to
+// pan and scroll to zoom. Each viewport change cancels whatever render is
+// still in flight and starts a fresh one, so the display always converges
+// on the latest requested view instead of queuing up stale ones.
+func runInteractive(v Viewport) {
+	pixelgl.Run(func() {
+		cfg := pixelgl.WindowConfig{
+			Title:  "Parallel Mandelbrot",
+			Bounds: pixel.R(0, 0, float64(v.Width), float64(v.Height)),
+			VSync:  true,
+		}
+		win, err := pixelgl.NewWindow(cfg)
+		if err != nil {
+			panic(err)
+		}
+
+		var (
+			cancel   context.CancelFunc
+			sprite   *pixel.Sprite
+			dragging bool
+			lastPos  pixel.Vec
+		)
+
+		render := func() {
+			if cancel != nil {
+				cancel()
+			}
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func(ctx context.Context, v Viewport) {
+				img := v.Render(ctx)
+				if ctx.Err() != nil {
+					return
+				}
+				picture := pixel.PictureDataFromImage(img)
+				sprite = pixel.NewSprite(picture, picture.Bounds())
+			}(ctx, v)
+		}
+		render()
+
+		for !win.Closed() {
+			if win.JustPressed(pixelgl.MouseButtonLeft) {
+				dragging = true
+				lastPos = win.MousePosition()
+			}
+			if win.JustReleased(pixelgl.MouseButtonLeft) {
+				dragging = false
+			}
+			if dragging {
+				pos := win.MousePosition()
+				delta := pos.Sub(lastPos)
+				lastPos = pos
+				halfIm := 1.0 / v.Zoom
+				halfRe := halfIm * float64(v.Width) / float64(v.Height)
+				v.CenterRe -= delta.X / float64(v.Width) * 2 * halfRe
+				v.CenterIm -= delta.Y / float64(v.Height) * 2 * halfIm
+				render()
+			}
+			if scroll := win.MouseScroll(); scroll.Y != 0 {
+				v.Zoom *= math.Pow(1.2, scroll.Y)
+				v.MaxIter = 0 // re-auto-scale MaxIter for the new zoom
+				render()
+			}
+
+			win.Clear(colornames.Black)
+			if sprite != nil {
+				sprite.Draw(win, pixel.IM.Moved(win.Bounds().Center()))
+			}
+			win.Update()
+		}
+	})
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+)
+
+// minRegionSize is the smallest region the Mariani-Silver scheduler will
+// still try to subdivide. Below this size the savings from border-checking
+// don't outweigh the overhead of doing so, so the region is simply computed
+// pixel-by-pixel. Since borderUniform (see below) only flat-fills regions
+// that are provably interior to the set, an escaped region never fills and
+// always pays for every border check down to this size before falling back
+// to per-pixel - too small a value here buys the interior fill nothing extra
+// while making every escaped region strictly more expensive than computing
+// it directly. 64 is the smallest value that still shows a net win on both
+// of BenchmarkMarianiSilver's standard views; see its doc comment.
+const minRegionSize = 64
+
+// pixelMsg is anything calculatorWorker can send to drawingWorker to be
+// drawn into the output image. A uniform region is drawn as a single
+// pixelBatch instead of one message per pixel, which is what actually lets
+// the scheduler's savings show up as fewer channel sends, not just fewer
+// kernel evaluations.
+type pixelMsg interface {
+	// draw paints the message into img and returns how many pixels it drew.
+	draw(img *image.RGBA) int
+}
+
+type singlePixel struct {
+	x, y int
+	col  color.RGBA
+}
+
+func (p singlePixel) draw(img *image.RGBA) int {
+	img.Set(p.x, p.y, p.col)
+	return 1
+}
+
+type pixelBatch struct {
+	minX, maxX, minY, maxY int
+	col                    color.RGBA
+}
+
+func (b pixelBatch) draw(img *image.RGBA) int {
+	n := 0
+	for y := b.minY; y < b.maxY; y++ {
+		for x := b.minX; x < b.maxX; x++ {
+			img.Set(x, y, b.col)
+			n++
+		}
+	}
+	return n
+}
+
+// workQueue is an unbounded LIFO deque of pending regions guarded by a task
+// counter: pending tracks regions that are queued or still being processed,
+// and pop unblocks every waiting worker as soon as it reaches zero, since at
+// that point no worker can possibly produce more work. Subdividing a region
+// pushes its children (pending += 4) before marking the parent done
+// (pending -= 1), so pending never hits zero while there is still a region
+// that might split further.
+type workQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	items   []workRange
+	pending int
+	closed  bool
+}
+
+func newWorkQueue() *workQueue {
+	q := &workQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *workQueue) push(w workRange) {
+	q.mu.Lock()
+	q.items = append(q.items, w)
+	q.pending++
+	q.cond.Signal()
+	q.mu.Unlock()
+}
+
+// pop blocks until a region is available or the queue is drained, in which
+// case it returns ok=false so the calling worker can exit.
+func (q *workQueue) pop() (w workRange, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return workRange{}, false
+	}
+	last := len(q.items) - 1
+	w = q.items[last]
+	q.items = q.items[:last]
+	return w, true
+}
+
+// taskDone marks one popped (or never-popped, for the initial seed) region
+// as finished. Call it exactly once per push, whether the region was filled
+// directly or split into children (in which case it's called after the
+// children have already been pushed).
+func (q *workQueue) taskDone() {
+	q.mu.Lock()
+	q.pending--
+	if q.pending == 0 {
+		q.closed = true
+		q.cond.Broadcast()
+	}
+	q.mu.Unlock()
+}
+
+// workCreator seeds the scheduler with region (the whole image, or a single
+// tile of it); calculatorWorker recursively subdivides it from there.
+func workCreator(queue *workQueue, region workRange) {
+	queue.push(region)
+}
+
+// evalBorder samples the pixel centers along w's perimeter through kernel.
+func evalBorder(w workRange, p renderParams, kernel KernelFunc) []escapeResult {
+	var xs, ys []int
+	for x := w.minX; x < w.maxX; x++ {
+		xs = append(xs, x, x)
+		ys = append(ys, w.minY, w.maxY-1)
+	}
+	for y := w.minY + 1; y < w.maxY-1; y++ {
+		xs = append(xs, w.minX, w.maxX-1)
+		ys = append(ys, y, y)
+	}
+
+	cr := make([]float64, len(xs))
+	ci := make([]float64, len(xs))
+	for i := range xs {
+		cr[i] = p.reStart + (float64(xs[i])+0.5)/float64(p.imgWidth)*(p.reEnd-p.reStart)
+		ci[i] = p.imStart + (float64(ys[i])+0.5)/float64(p.imgHeight)*(p.imEnd-p.imStart)
+	}
+	return evalPoints(cr, ci, kernel, p.maxIter)
+}
+
+// borderUniform reports whether every sample escaped at the same iteration
+// (including all never escaping). Matching iter is necessary for the
+// interior to be assumed uniform too, but it's only sufficient when the
+// border never escaped: smoothIter is continuous, so escaped samples that
+// share an iter can still disagree on abs2 (and hence on mu/color)
+// depending on exactly where they sit in the plane, while points that
+// never escape all resolve to the same mu == maxIter by definition. A
+// uniform-but-escaped border is reported as uniform = false so the caller
+// falls back to subdividing or rendering the region pixel-by-pixel instead
+// of flat-filling it with one border sample's color.
+func borderUniform(border []escapeResult, maxIter int) (escapeResult, bool) {
+	if len(border) == 0 {
+		return escapeResult{}, false
+	}
+	first := border[0]
+	for _, r := range border[1:] {
+		if r.iter != first.iter {
+			return escapeResult{}, false
+		}
+	}
+	if first.iter < maxIter {
+		return escapeResult{}, false
+	}
+	return first, true
+}
+
+// renderRegionPixels computes and sends every pixel of w individually, with
+// supersampling as configured on w.samples. This is the pre-Mariani-Silver
+// per-pixel path, used once a region is too small to be worth subdividing
+// or border-checking further.
+func renderRegionPixels(ctx context.Context, w workRange, p renderParams, kernel KernelFunc, palette Palette, pixelBuffer chan<- pixelMsg) {
+	samples := w.samples
+	if samples < 1 {
+		samples = 1
+	}
+	perPixel := samples * samples
+
+	for y := w.minY; y < w.maxY; y++ {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		for x := w.minX; x < w.maxX; x++ {
+			cr := make([]float64, 0, perPixel)
+			ci := make([]float64, 0, perPixel)
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					re := float64(x) + (float64(sx)+0.5)/float64(samples)
+					im := float64(y) + (float64(sy)+0.5)/float64(samples)
+					cr = append(cr, p.reStart+re/float64(p.imgWidth)*(p.reEnd-p.reStart))
+					ci = append(ci, p.imStart+im/float64(p.imgHeight)*(p.imEnd-p.imStart))
+				}
+			}
+
+			results := evalPoints(cr, ci, kernel, p.maxIter)
+			select {
+			case pixelBuffer <- singlePixel{x, y, averageColor(results, palette, p.maxIter)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// processRegion implements one step of the Mariani-Silver algorithm for a
+// single region popped off queue: check the border, fill if uniform, else
+// split if the region is still big enough, else fall back to computing it
+// pixel-by-pixel.
+func processRegion(ctx context.Context, w workRange, p renderParams, kernel KernelFunc, palette Palette, pixelBuffer chan<- pixelMsg, queue *workQueue) {
+	defer queue.taskDone()
+
+	select {
+	case <-ctx.Done():
+		return
+	default:
+	}
+
+	regionWidth := w.maxX - w.minX
+	regionHeight := w.maxY - w.minY
+	if regionWidth <= 0 || regionHeight <= 0 {
+		return
+	}
+
+	if border, uniform := borderUniform(evalBorder(w, p, kernel), p.maxIter); uniform {
+		col := palette.Color(smoothIter(border, p.maxIter), p.maxIter)
+		select {
+		case pixelBuffer <- pixelBatch{w.minX, w.maxX, w.minY, w.maxY, col}:
+		case <-ctx.Done():
+		}
+		return
+	}
+
+	if regionWidth > minRegionSize && regionHeight > minRegionSize {
+		midX := (w.minX + w.maxX) / 2
+		midY := (w.minY + w.maxY) / 2
+		for _, child := range [4]workRange{
+			{w.minX, midX, w.minY, midY, w.samples},
+			{midX, w.maxX, w.minY, midY, w.samples},
+			{w.minX, midX, midY, w.maxY, w.samples},
+			{midX, w.maxX, midY, w.maxY, w.samples},
+		} {
+			queue.push(child)
+		}
+		return
+	}
+
+	renderRegionPixels(ctx, w, p, kernel, palette, pixelBuffer)
+}
+
+// calculatorWorkerStarter starts a fixed pool of numThreads goroutines that
+// pull regions from queue until it's drained, each running processRegion
+// (which may push subdivided regions back onto queue for any worker to
+// pick up).
+func calculatorWorkerStarter(ctx context.Context, queue *workQueue, p renderParams, kernel KernelFunc, palette Palette, pixelBuffer chan<- pixelMsg, workers *sync.WaitGroup) {
+	for t := 0; t < numThreads; t++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				w, ok := queue.pop()
+				if !ok {
+					return
+				}
+				processRegion(ctx, w, p, kernel, palette, pixelBuffer, queue)
+			}
+		}()
+	}
+}
+
+// drawingWorker draws pixel messages as they arrive until total pixels have
+// been drawn, the buffer is closed, or ctx is cancelled (in which case the
+// render is abandoned with whatever was drawn so far).
+func drawingWorker(ctx context.Context, pixelBuffer <-chan pixelMsg, img *image.RGBA, pixelCount *int, total int, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer fmt.Println()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-pixelBuffer:
+			if !ok {
+				return
+			}
+			before := *pixelCount
+			*pixelCount += msg.draw(img)
+			if printDrawingProgress {
+				for mark := before + 1; mark <= *pixelCount; mark++ {
+					if mark%(total/10+1) == 0 {
+						fmt.Print("█")
+					}
+				}
+			}
+			if *pixelCount >= total {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"image"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// benchViews are the "standard views" BenchmarkUniformTiler and
+// BenchmarkMarianiSilver render to compare how many points each scheduler
+// actually runs through the kernel. Since borderUniform only flat-fills
+// regions that never escape, the saving comes entirely from the set's
+// interior blobs (the cardioid/bulbs and their neighbors); both FullSet and
+// ZoomedBoundary contain enough of that to net fewer evaluated points than
+// the uniform tiler, but neither is dramatic - see minRegionSize's doc
+// comment for why escaped regions can't contribute a saving here.
+var benchViews = []struct {
+	name string
+	v    Viewport
+}{
+	{"FullSet", Viewport{CenterRe: -0.5, CenterIm: 0, Zoom: 1, Width: 512, Height: 512, MaxIter: 100}},
+	{"ZoomedBoundary", Viewport{CenterRe: -0.75, CenterIm: 0.1, Zoom: 50, Width: 512, Height: 512, MaxIter: 200}},
+}
+
+// renderUniformTile reproduces the pre-Mariani-Silver uniform tiler's total
+// work: every pixel of the view is run through the kernel individually,
+// with no border check or subdivision.
+func renderUniformTile(ctx context.Context, p renderParams) {
+	pixelBuffer := make(chan pixelMsg, numThreads*4)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range pixelBuffer {
+		}
+	}()
+	renderRegionPixels(ctx, workRange{0, p.imgWidth, 0, p.imgHeight, SAMPLES}, p, mandelbrotCalc, activePalette, pixelBuffer)
+	close(pixelBuffer)
+	wg.Wait()
+}
+
+// renderNaive draws every pixel of p's grid individually, the same way
+// renderUniformTile exercises the kernel but into an actual image so its
+// output can be compared against Viewport.Render's Mariani-Silver path.
+func renderNaive(ctx context.Context, p renderParams) *image.RGBA {
+	region := workRange{0, p.imgWidth, 0, p.imgHeight, SAMPLES}
+	img := image.NewRGBA(image.Rect(0, 0, p.imgWidth, p.imgHeight))
+	pixelBuffer := make(chan pixelMsg, numThreads*4)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for msg := range pixelBuffer {
+			msg.draw(img)
+		}
+	}()
+	renderRegionPixels(ctx, region, p, mandelbrotCalc, activePalette, pixelBuffer)
+	close(pixelBuffer)
+	wg.Wait()
+	return img
+}
+
+// TestMarianiSilverMatchesNaive guards against the scheduler's region
+// fills disagreeing with what rendering every pixel individually would
+// produce. Before the borderUniform fix this caught the scheduler
+// flat-filling escaped-but-same-iter regions with a single border sample's
+// smooth-colored mu, which visibly banded at region boundaries even though
+// every border pixel reported the same raw iter.
+func TestMarianiSilverMatchesNaive(t *testing.T) {
+	v := Viewport{CenterRe: -0.5, CenterIm: 0, Zoom: 1, Width: 100, Height: 80, MaxIter: 100}
+	p := v.bounds()
+
+	got := v.Render(context.Background())
+	want := renderNaive(context.Background(), p)
+
+	mismatches := 0
+	for y := 0; y < p.imgHeight; y++ {
+		for x := 0; x < p.imgWidth; x++ {
+			if got.RGBAAt(x, y) != want.RGBAAt(x, y) {
+				mismatches++
+			}
+		}
+	}
+	if mismatches != 0 {
+		t.Errorf("Mariani-Silver render disagrees with naive per-pixel render on %d/%d pixels", mismatches, p.imgWidth*p.imgHeight)
+	}
+}
+
+func BenchmarkUniformTiler(b *testing.B) {
+	for _, bv := range benchViews {
+		p := bv.v.bounds()
+		b.Run(bv.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				atomic.StoreInt64(&evaluatedPoints, 0)
+				renderUniformTile(context.Background(), p)
+				b.ReportMetric(float64(atomic.LoadInt64(&evaluatedPoints)), "points/op")
+			}
+		})
+	}
+}
+
+func BenchmarkMarianiSilver(b *testing.B) {
+	for _, bv := range benchViews {
+		b.Run(bv.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				atomic.StoreInt64(&evaluatedPoints, 0)
+				bv.v.Render(context.Background())
+				b.ReportMetric(float64(atomic.LoadInt64(&evaluatedPoints)), "points/op")
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// Stitch assembles the tiles recorded in outDir's manifest into a single
+// PNG at destPath. Each tile file is memory-mapped (see mmapFile) rather
+// than read in full so stitching a gigapixel render doesn't cost gigapixels
+// of extra heap on top of the final image itself.
+func Stitch(outDir, destPath string) error {
+	manifest, err := loadManifest(filepath.Join(outDir, "manifest.json"))
+	if err != nil {
+		return err
+	}
+	if len(manifest.Completed) == 0 {
+		return fmt.Errorf("stitch: no completed tiles recorded in %s", outDir)
+	}
+
+	full := image.NewRGBA(image.Rect(0, 0, manifest.Width, manifest.Height))
+
+	tilesX := (manifest.Width + manifest.TileSize - 1) / manifest.TileSize
+	tilesY := (manifest.Height + manifest.TileSize - 1) / manifest.TileSize
+
+	for j := 0; j < tilesY; j++ {
+		for i := 0; i < tilesX; i++ {
+			key := tileKey(i, j)
+			if !manifest.Completed[key] {
+				return fmt.Errorf("stitch: missing tile %s (render with -resume first)", key)
+			}
+
+			path := tilePath(outDir, i, j)
+			data, unmap, err := mmapFile(path)
+			if err != nil {
+				return fmt.Errorf("stitch: %w", err)
+			}
+			tile, err := png.Decode(bytes.NewReader(data))
+			unmap()
+			if err != nil {
+				return fmt.Errorf("stitch: decode %s: %w", path, err)
+			}
+
+			origin := image.Pt(i*manifest.TileSize, j*manifest.TileSize)
+			dst := image.Rectangle{Min: origin, Max: origin.Add(tile.Bounds().Size())}
+			draw.Draw(full, dst, tile, tile.Bounds().Min, draw.Src)
+		}
+	}
+
+	f, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, full)
+}
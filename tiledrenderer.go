@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// tileManifest records which tiles of a TiledRenderer run have finished, so
+// a later run with Resume set can skip straight to the ones still missing.
+type tileManifest struct {
+	TileSize  int             `json:"tileSize"`
+	Width     int             `json:"width"`
+	Height    int             `json:"height"`
+	Completed map[string]bool `json:"completed"`
+}
+
+func tileKey(i, j int) string {
+	return fmt.Sprintf("%d_%d", i, j)
+}
+
+func tilePath(outDir string, i, j int) string {
+	return filepath.Join(outDir, fmt.Sprintf("tile_%d_%d.png", i, j))
+}
+
+func loadManifest(path string) (*tileManifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &tileManifest{Completed: map[string]bool{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m tileManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m.Completed == nil {
+		m.Completed = map[string]bool{}
+	}
+	return &m, nil
+}
+
+// save writes the manifest to a temp file and renames it into place, so a
+// crash mid-write can never leave a corrupt manifest behind.
+func (m *tileManifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// TiledRenderer renders a Viewport tile by tile, writing each finished tile
+// straight to its own PNG under OutDir as soon as it's done instead of
+// accumulating the whole image in memory, which is what makes gigapixel
+// renders practical on modest RAM. OutDir/manifest.json records completed
+// tiles so a crashed or interrupted run can be restarted with Resume set
+// instead of starting over.
+type TiledRenderer struct {
+	Viewport Viewport
+	TileSize int
+	OutDir   string
+	Resume   bool
+}
+
+// Render writes every tile of tr.Viewport to tr.OutDir, skipping tiles
+// already recorded as done in the manifest when tr.Resume is set.
+func (tr TiledRenderer) Render(ctx context.Context) error {
+	tileSize := tr.TileSize
+	if tileSize <= 0 {
+		tileSize = 512
+	}
+	if err := os.MkdirAll(tr.OutDir, 0o755); err != nil {
+		return err
+	}
+
+	manifestPath := filepath.Join(tr.OutDir, "manifest.json")
+	manifest := &tileManifest{Completed: map[string]bool{}}
+	if tr.Resume {
+		m, err := loadManifest(manifestPath)
+		if err != nil {
+			return err
+		}
+		manifest = m
+	}
+
+	p := tr.Viewport.bounds()
+	manifest.TileSize = tileSize
+	manifest.Width = p.imgWidth
+	manifest.Height = p.imgHeight
+
+	tilesX := (p.imgWidth + tileSize - 1) / tileSize
+	tilesY := (p.imgHeight + tileSize - 1) / tileSize
+
+	for j := 0; j < tilesY; j++ {
+		for i := 0; i < tilesX; i++ {
+			key := tileKey(i, j)
+			if tr.Resume && manifest.Completed[key] {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			region := workRange{
+				minX:    i * tileSize,
+				maxX:    min(p.imgWidth, (i+1)*tileSize),
+				minY:    j * tileSize,
+				maxY:    min(p.imgHeight, (j+1)*tileSize),
+				samples: SAMPLES,
+			}
+			tile := renderRegion(ctx, p, region)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := writeTilePNG(tilePath(tr.OutDir, i, j), tile); err != nil {
+				return err
+			}
+
+			manifest.Completed[key] = true
+			if err := manifest.save(manifestPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeTilePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, img)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"image"
+	"math"
+	"sync"
+)
+
+// Viewport describes one view of the complex plane to render: a center
+// point, a zoom factor (1 shows the same real-axis span the original
+// RE_START/RE_END/IM_START/IM_END constants did), and the output pixel
+// grid. It replaces the package-level RE_START/RE_END/IM_START/IM_END/
+// width/height/MAX_ITER globals the renderer used to read directly.
+type Viewport struct {
+	CenterRe, CenterIm float64
+	Zoom               float64
+	Width, Height      int
+	MaxIter            int // 0 auto-scales with Zoom, see bounds()
+}
+
+// bounds resolves the viewport into the plane bounds and iteration cap the
+// worker pipeline actually renders with.
+func (v Viewport) bounds() renderParams {
+	halfIm := 1.0 / v.Zoom
+	halfRe := halfIm * float64(v.Width) / float64(v.Height)
+
+	maxIter := v.MaxIter
+	if maxIter <= 0 {
+		// Deep zooms need more iterations to keep detail crisp; scale
+		// roughly logarithmically with zoom rather than paying the cost
+		// of a high fixed cap at zoom 1.
+		maxIter = int(256 * math.Log10(v.Zoom+10))
+	}
+
+	return renderParams{
+		reStart:   v.CenterRe - halfRe,
+		reEnd:     v.CenterRe + halfRe,
+		imStart:   v.CenterIm - halfIm,
+		imEnd:     v.CenterIm + halfIm,
+		imgWidth:  v.Width,
+		imgHeight: v.Height,
+		maxIter:   maxIter,
+	}
+}
+
+// Render drives the Mariani-Silver worker/channel pipeline for this
+// viewport's full pixel grid and returns the resulting image. Cancelling
+// ctx abandons any in-flight calculatorWorkers and returns the
+// partially-drawn image immediately, which front-ends use to drop a stale
+// render as soon as the viewport changes again.
+func (v Viewport) Render(ctx context.Context) *image.RGBA {
+	p := v.bounds()
+	return renderRegion(ctx, p, workRange{0, p.imgWidth, 0, p.imgHeight, SAMPLES})
+}
+
+// renderRegion runs the worker/channel pipeline (workCreator,
+// calculatorWorkerStarter, drawingWorker) for a single region of p's pixel
+// grid and returns it as an image whose Bounds match region exactly -
+// TiledRenderer uses this to render one tile at a time instead of the
+// whole viewport.
+func renderRegion(ctx context.Context, p renderParams, region workRange) *image.RGBA {
+	img := image.NewRGBA(image.Rect(region.minX, region.minY, region.maxX, region.maxY))
+	total := (region.maxX - region.minX) * (region.maxY - region.minY)
+	pixelCount := 0
+
+	pixelBuffer := make(chan pixelMsg, numThreads*4)
+	queue := newWorkQueue()
+	workCreator(queue, region)
+
+	var workers sync.WaitGroup
+	calculatorWorkerStarter(ctx, queue, p, mandelbrotCalc, activePalette, pixelBuffer, &workers)
+	go func() {
+		workers.Wait()
+		close(pixelBuffer)
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go drawingWorker(ctx, pixelBuffer, img, &pixelCount, total, &wg)
+	wg.Wait()
+
+	return img
+}